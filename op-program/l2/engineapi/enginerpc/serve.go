@@ -0,0 +1,187 @@
+// Package enginerpc exposes an engineapi.L2EngineAPI over an authenticated JSON-RPC server. It is
+// kept separate from package engineapi so that the op-program fault-proof client, which must run
+// deterministically under cannon/MIPS, never links the networked RPC server, JWT, and filesystem
+// code this file pulls in merely by importing the engine API type it is built around.
+package enginerpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/ethereum-optimism/optimism/op-node/eth"
+	"github.com/ethereum-optimism/optimism/op-program/l2/engineapi"
+)
+
+// Config configures the authenticated HTTP+WS server that exposes the L2EngineAPI, mirroring
+// upstream go-ethereum's authrpc.addr/authrpc.port/authrpc.vhosts/authrpc.jwtsecret flags so that
+// real consensus/rollup drivers can be pointed at this in-memory engine backend.
+type Config struct {
+	Addr    string
+	Port    int
+	VHosts  []string
+	JWTPath string
+}
+
+// DefaultConfig returns the upstream go-ethereum authrpc defaults.
+func DefaultConfig() *Config {
+	return &Config{
+		Addr:   "127.0.0.1",
+		Port:   8551,
+		VHosts: []string{"localhost"},
+	}
+}
+
+// Serve starts an authenticated HTTP+WS server exposing the engine_* and a read-only subset of
+// eth_* methods on ea, using a go-ethereum node.Node so that the auth port gets the same JWT
+// validation (HS256, ±60s `iat` freshness window per the Engine API spec), vhost checks, and
+// method allow-listing as a real execution client.
+func Serve(ea *engineapi.L2EngineAPI, cfg *Config) (*node.Node, error) {
+	if _, err := ObtainJWTSecret(cfg.JWTPath); err != nil {
+		return nil, fmt.Errorf("failed to obtain jwt secret: %w", err)
+	}
+	nodeCfg := &node.Config{
+		Name:             "l2-engine-api",
+		AuthAddr:         cfg.Addr,
+		AuthPort:         cfg.Port,
+		AuthVirtualHosts: cfg.VHosts,
+		// node.Config.JWTSecret is a filesystem path that go-ethereum reads the hex secret from
+		// itself, not the secret value: ObtainJWTSecret above already made sure cfg.JWTPath holds
+		// the right bytes, so just point the node at that same file.
+		JWTSecret: cfg.JWTPath,
+	}
+	n, err := node.New(nodeCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rpc node: %w", err)
+	}
+	n.RegisterAPIs(rpcAPIs(ea))
+	if err := n.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start rpc node: %w", err)
+	}
+	log.Info("L2EngineAPI auth-rpc server started", "addr", cfg.Addr, "port", cfg.Port)
+	return n, nil
+}
+
+// rpcAPIs returns the engine_* methods plus a read-only eth_* subset, all marked Authenticated so
+// go-ethereum's node.Node only ever exposes them on the JWT-gated auth port.
+func rpcAPIs(ea *engineapi.L2EngineAPI) []rpc.API {
+	return []rpc.API{
+		{
+			Namespace:     "engine",
+			Service:       &engineSpecAPI{ea},
+			Authenticated: true,
+		},
+		{
+			Namespace:     "eth",
+			Service:       &readOnlyEthAPI{ea},
+			Authenticated: true,
+		},
+	}
+}
+
+// engineSpecAPI exposes only the Engine API spec methods over RPC. L2EngineAPI itself also carries
+// test/driver-only exported methods (IncludeTx, RemainingBlockGas, SetTxSource, InvalidHeader,
+// ...) that must never be reachable as authenticated engine_* RPCs, so it is never registered with
+// the RPC server directly; this thin wrapper is registered instead.
+type engineSpecAPI struct {
+	ea *engineapi.L2EngineAPI
+}
+
+func (a *engineSpecAPI) ForkchoiceUpdatedV1(ctx context.Context, state *eth.ForkchoiceState, attr *eth.PayloadAttributes) (*eth.ForkchoiceUpdatedResult, error) {
+	return a.ea.ForkchoiceUpdatedV1(ctx, state, attr)
+}
+
+func (a *engineSpecAPI) ForkchoiceUpdatedV2(ctx context.Context, state *eth.ForkchoiceState, attr *eth.PayloadAttributes) (*eth.ForkchoiceUpdatedResult, error) {
+	return a.ea.ForkchoiceUpdatedV2(ctx, state, attr)
+}
+
+func (a *engineSpecAPI) ForkchoiceUpdatedV3(ctx context.Context, state *eth.ForkchoiceState, attr *eth.PayloadAttributes) (*eth.ForkchoiceUpdatedResult, error) {
+	return a.ea.ForkchoiceUpdatedV3(ctx, state, attr)
+}
+
+func (a *engineSpecAPI) GetPayloadV1(ctx context.Context, payloadId eth.PayloadID) (*eth.ExecutionPayload, error) {
+	return a.ea.GetPayloadV1(ctx, payloadId)
+}
+
+func (a *engineSpecAPI) GetPayloadV2(ctx context.Context, payloadId eth.PayloadID) (*eth.ExecutionPayloadEnvelope, error) {
+	return a.ea.GetPayloadV2(ctx, payloadId)
+}
+
+func (a *engineSpecAPI) GetPayloadV3(ctx context.Context, payloadId eth.PayloadID) (*eth.ExecutionPayloadEnvelope, error) {
+	return a.ea.GetPayloadV3(ctx, payloadId)
+}
+
+func (a *engineSpecAPI) NewPayloadV1(ctx context.Context, payload *eth.ExecutionPayload) (*eth.PayloadStatusV1, error) {
+	return a.ea.NewPayloadV1(ctx, payload)
+}
+
+func (a *engineSpecAPI) NewPayloadV2(ctx context.Context, payload *eth.ExecutionPayload) (*eth.PayloadStatusV1, error) {
+	return a.ea.NewPayloadV2(ctx, payload)
+}
+
+func (a *engineSpecAPI) NewPayloadV3(ctx context.Context, payload *eth.ExecutionPayload, versionedHashes []common.Hash, parentBeaconBlockRoot *common.Hash) (*eth.PayloadStatusV1, error) {
+	return a.ea.NewPayloadV3(ctx, payload, versionedHashes, parentBeaconBlockRoot)
+}
+
+func (a *engineSpecAPI) ExchangeTransitionConfigurationV1(ctx context.Context, cfg engine.TransitionConfigurationV1) (*engine.TransitionConfigurationV1, error) {
+	return a.ea.ExchangeTransitionConfigurationV1(ctx, cfg)
+}
+
+// readOnlyEthAPI exposes the small set of eth_* chain-read methods that upstream CL clients poll
+// over the auth port (e.g. to sanity check the EL's view of the chain tip).
+type readOnlyEthAPI struct {
+	ea *engineapi.L2EngineAPI
+}
+
+func (a *readOnlyEthAPI) ChainId() hexutil.Big {
+	return hexutil.Big(*a.ea.ChainID())
+}
+
+func (a *readOnlyEthAPI) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(a.ea.CurrentBlockNumber())
+}
+
+// ObtainJWTSecret loads the given JWT secret file, generating and persisting a random 32-byte
+// secret if it does not yet exist. This matches upstream go-ethereum's handling of the
+// authrpc.jwtsecret flag when no consensus client has provisioned the file up front.
+func ObtainJWTSecret(fileName string) ([32]byte, error) {
+	var secret [32]byte
+	if data, err := os.ReadFile(fileName); err == nil {
+		jwtSecret := strings.TrimPrefix(strings.TrimSpace(string(data)), "0x")
+		if len(jwtSecret) != 64 {
+			return secret, fmt.Errorf("invalid JWT secret in %s, expected 32 hex-encoded bytes", fileName)
+		}
+		decoded, err := hex.DecodeString(jwtSecret)
+		if err != nil {
+			return secret, fmt.Errorf("invalid JWT secret in %s: %w", fileName, err)
+		}
+		copy(secret[:], decoded)
+		return secret, nil
+	} else if !os.IsNotExist(err) {
+		return secret, fmt.Errorf("failed to read JWT secret file %s: %w", fileName, err)
+	}
+
+	log.Warn("Generating random JWT secret", "path", fileName)
+	if _, err := io.ReadFull(rand.Reader, secret[:]); err != nil {
+		return secret, fmt.Errorf("failed to generate JWT secret: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return secret, fmt.Errorf("failed to create JWT secret directory: %w", err)
+	}
+	if err := os.WriteFile(fileName, []byte(hexutil.Encode(secret[:])), 0600); err != nil {
+		return secret, fmt.Errorf("failed to persist JWT secret to %s: %w", fileName, err)
+	}
+	return secret, nil
+}
@@ -0,0 +1,47 @@
+package enginerpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObtainJWTSecretGeneratesAndPersistsWhenMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwt.hex")
+
+	secret, err := ObtainJWTSecret(path)
+	require.NoError(t, err)
+	require.NotEqual(t, [32]byte{}, secret)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Encode(secret[:]), string(data))
+
+	// a second call must load the now-persisted secret back unchanged rather than regenerating it
+	reloaded, err := ObtainJWTSecret(path)
+	require.NoError(t, err)
+	require.Equal(t, secret, reloaded)
+}
+
+func TestObtainJWTSecretTrimsWhitespaceAnd0xPrefix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwt.hex")
+	raw := hex.EncodeToString(bytes.Repeat([]byte{0xAB}, 32))
+	require.NoError(t, os.WriteFile(path, []byte("  0x"+raw+"\n"), 0600))
+
+	secret, err := ObtainJWTSecret(path)
+	require.NoError(t, err)
+	require.Equal(t, raw, hex.EncodeToString(secret[:]))
+}
+
+func TestObtainJWTSecretRejectsBadLength(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwt.hex")
+	require.NoError(t, os.WriteFile(path, []byte("deadbeef"), 0600))
+
+	_, err := ObtainJWTSecret(path)
+	require.Error(t, err)
+}
@@ -0,0 +1,34 @@
+package engineapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExchangeTransitionConfigurationV1TTDMismatch is the regression test for 2ac5e05: a
+// consensus client reporting a different TTD than this engine is configured with must get an
+// error, matching upstream go-ethereum, rather than a 200 response silently reporting our own view.
+func TestExchangeTransitionConfigurationV1TTDMismatch(t *testing.T) {
+	ea := newTestEngineAPI(t, &fakeBackend{config: &params.ChainConfig{TerminalTotalDifficulty: big.NewInt(100)}})
+
+	_, err := ea.ExchangeTransitionConfigurationV1(context.Background(), engine.TransitionConfigurationV1{
+		TerminalTotalDifficulty: (*hexutil.Big)(big.NewInt(200)),
+	})
+	require.Error(t, err)
+}
+
+func TestExchangeTransitionConfigurationV1Match(t *testing.T) {
+	ea := newTestEngineAPI(t, &fakeBackend{config: &params.ChainConfig{TerminalTotalDifficulty: big.NewInt(100)}})
+
+	cfg, err := ea.ExchangeTransitionConfigurationV1(context.Background(), engine.TransitionConfigurationV1{
+		TerminalTotalDifficulty: (*hexutil.Big)(big.NewInt(100)),
+	})
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(100), cfg.TerminalTotalDifficulty.ToInt())
+}
@@ -0,0 +1,67 @@
+package engineapi
+
+import (
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// fakeBackend implements EngineBackend with just enough behavior for these tests: CurrentBlock,
+// Config, GetVMConfig (an empty vm.Config), BeaconSync (recording every call so dedup behavior can
+// be asserted), lookups against the headers/blocks maps below, and StateAt returning the
+// stateDB/stateErr fields below when a test populates them. Anything else panics, so a test
+// accidentally depending on unmocked backend behavior fails loudly rather than silently returning
+// a zero value.
+type fakeBackend struct {
+	config  *params.ChainConfig
+	current *types.Header
+
+	headers  map[common.Hash]*types.Header
+	blocks   map[common.Hash]*types.Block
+	stateDB  *state.StateDB
+	stateErr error
+
+	beaconSyncCalls []common.Hash
+}
+
+func (f *fakeBackend) CurrentBlock() *types.Header {
+	if f.current != nil {
+		return f.current
+	}
+	return &types.Header{}
+}
+
+func (f *fakeBackend) CurrentSafeBlock() *types.Header  { panic("not implemented") }
+func (f *fakeBackend) CurrentFinalBlock() *types.Header { panic("not implemented") }
+
+func (f *fakeBackend) GetHeaderByHash(hash common.Hash) *types.Header { return f.headers[hash] }
+func (f *fakeBackend) GetBlockByHash(hash common.Hash) *types.Block   { return f.blocks[hash] }
+func (f *fakeBackend) GetBlock(hash common.Hash, number uint64) *types.Block {
+	panic("not implemented")
+}
+func (f *fakeBackend) GetHeader(common.Hash, uint64) *types.Header { panic("not implemented") }
+func (f *fakeBackend) HasBlockAndState(hash common.Hash, number uint64) bool {
+	panic("not implemented")
+}
+func (f *fakeBackend) GetCanonicalHash(n uint64) common.Hash { panic("not implemented") }
+func (f *fakeBackend) GetVMConfig() *vm.Config               { return &vm.Config{} }
+func (f *fakeBackend) Config() *params.ChainConfig           { return f.config }
+func (f *fakeBackend) Engine() consensus.Engine              { panic("not implemented") }
+func (f *fakeBackend) StateAt(root common.Hash) (*state.StateDB, error) {
+	return f.stateDB, f.stateErr
+}
+func (f *fakeBackend) InsertBlockWithoutSetHead(block *types.Block) error  { panic("not implemented") }
+func (f *fakeBackend) SetCanonical(head *types.Block) (common.Hash, error) { panic("not implemented") }
+func (f *fakeBackend) SetFinalized(header *types.Header)                   { panic("not implemented") }
+func (f *fakeBackend) SetSafe(header *types.Header)                        { panic("not implemented") }
+
+func (f *fakeBackend) BeaconSync(head common.Hash) error {
+	f.beaconSyncCalls = append(f.beaconSyncCalls, head)
+	return nil
+}
+
+func (f *fakeBackend) SyncProgress() *ethereum.SyncProgress { panic("not implemented") }
@@ -0,0 +1,73 @@
+package engineapi
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// improvePayloadInterval is how often the background build-improving loop tries to pack
+// additional pool transactions into an in-flight build before it is collected by GetPayload.
+const improvePayloadInterval = 500 * time.Millisecond
+
+// TxSource supplies pending transactions to the background payload-improving loop. This is
+// deliberately narrower than core/txpool.TxPool: the engine only ever needs "what's ready to
+// include right now", grouped by sender so the per-account nonce bookkeeping IncludeTx already
+// does keeps working unchanged.
+type TxSource interface {
+	PendingTxs() map[common.Address][]*types.Transaction
+}
+
+// SetTxSource wires a pending-tx source into the engine so that an in-flight build keeps
+// absorbing newly-available pool transactions between ForkchoiceUpdated and GetPayload, matching
+// what miner/worker does upstream instead of only ever sealing the deposit batch from attr.Transactions.
+// Once set, a background goroutine touches the in-flight build, so callers must not also drive
+// IncludeTx against it concurrently (action tests that want deterministic, single-threaded block
+// building should simply leave the tx source unset).
+func (ea *L2EngineAPI) SetTxSource(pool TxSource) {
+	ea.txSource = pool
+}
+
+// improvePayload runs until b is collected (b.done is closed by GetPayload), periodically pulling
+// newly-available pool transactions into the build so a CL that waits a while before calling
+// GetPayload gets a progressively fuller block back.
+func (ea *L2EngineAPI) improvePayload(b *blockBuilder) {
+	if ea.txSource == nil {
+		return
+	}
+	ticker := time.NewTicker(improvePayloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+			ea.packPending(b)
+		}
+	}
+}
+
+// packPending includes any pool transactions not yet part of b, skipping accounts whose next
+// expected nonce (per b.pendingIndices) isn't available in the pool yet.
+func (ea *L2EngineAPI) packPending(b *blockBuilder) {
+	b.mu.Lock()
+	forceEmpty := b.forceEmpty
+	b.mu.Unlock()
+	if forceEmpty {
+		return
+	}
+	for from, txs := range ea.txSource.PendingTxs() {
+		for {
+			b.mu.Lock()
+			next := int(b.pendingIndices[from])
+			b.mu.Unlock()
+			if next >= len(txs) {
+				break
+			}
+			if err := ea.includeTx(b, txs[next], from); err != nil {
+				break
+			}
+		}
+	}
+}
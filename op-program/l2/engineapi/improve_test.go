@@ -0,0 +1,111 @@
+package engineapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+// stubTxSource is a fixed, pre-populated TxSource for driving packPending directly in tests,
+// without needing a real tx pool.
+type stubTxSource struct {
+	txs map[common.Address][]*types.Transaction
+}
+
+func (s *stubTxSource) PendingTxs() map[common.Address][]*types.Transaction { return s.txs }
+
+// panicTxSource fails any test that reaches it, so a test can assert packPending never consults
+// the tx source at all under some condition (e.g. forceEmpty).
+type panicTxSource struct{}
+
+func (panicTxSource) PendingTxs() map[common.Address][]*types.Transaction {
+	panic("PendingTxs must not be called")
+}
+
+func TestPackPendingSkipsWhenForceEmpty(t *testing.T) {
+	ea := &L2EngineAPI{txSource: panicTxSource{}}
+	b := &blockBuilder{forceEmpty: true}
+
+	require.NotPanics(t, func() { ea.packPending(b) })
+}
+
+// newTestBuilder returns a blockBuilder ready to have transfer transactions applied to it: an
+// empty state, a funded gas pool, and a pre-London header so the transactions below can be signed
+// with the plain FrontierSigner instead of needing chain-ID/base-fee plumbing.
+func newTestBuilder(t *testing.T) *blockBuilder {
+	t.Helper()
+	header := &types.Header{Number: big.NewInt(1), GasLimit: 1_000_000, Difficulty: common.Big0}
+	return &blockBuilder{
+		header:         header,
+		state:          newTestState(t),
+		gasPool:        new(core.GasPool).AddGas(header.GasLimit),
+		pendingIndices: make(map[common.Address]uint64),
+		transactions:   make([]*types.Transaction, 0),
+		done:           make(chan struct{}),
+	}
+}
+
+// signTransferTx returns a zero-value, zero-gas-price transfer tx from key at nonce, valid against
+// an unfunded account on a pre-Homestead, pre-EIP155 chain config.
+func signTransferTx(t *testing.T, nonce uint64) *types.Transaction {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	to := common.HexToAddress("0x1234")
+	tx, err := types.SignTx(types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: big.NewInt(0),
+		Gas:      21_000,
+		To:       &to,
+		Value:    big.NewInt(0),
+	}), types.FrontierSigner{}, key)
+	require.NoError(t, err)
+	from, err := types.Sender(types.FrontierSigner{}, tx)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), from)
+	return tx
+}
+
+// TestPackPendingIncludesTxsUpToPendingIndices covers packPending's core behavior: every available
+// pending tx for an account gets included in nonce order, advancing pendingIndices as it goes.
+func TestPackPendingIncludesTxsUpToPendingIndices(t *testing.T) {
+	tx0 := signTransferTx(t, 0)
+	from, err := types.Sender(types.FrontierSigner{}, tx0)
+	require.NoError(t, err)
+
+	b := newTestBuilder(t)
+	backend := &fakeBackend{config: &params.ChainConfig{}}
+	ea := newTestEngineAPI(t, backend)
+	ea.SetTxSource(&stubTxSource{txs: map[common.Address][]*types.Transaction{from: {tx0}}})
+
+	ea.packPending(b)
+
+	require.Equal(t, uint64(1), b.pendingIndices[from])
+	require.Len(t, b.transactions, 1)
+	require.Equal(t, tx0.Hash(), b.transactions[0].Hash())
+}
+
+// TestPackPendingStopsAfterCollected is the regression test for the packPending side of the
+// c379fce race fix: once a build has been collected, packPending must not add any more txs to it,
+// even if the tx source still has some pending.
+func TestPackPendingStopsAfterCollected(t *testing.T) {
+	tx0 := signTransferTx(t, 0)
+	from, err := types.Sender(types.FrontierSigner{}, tx0)
+	require.NoError(t, err)
+
+	b := newTestBuilder(t)
+	b.close()
+	backend := &fakeBackend{config: &params.ChainConfig{}}
+	ea := newTestEngineAPI(t, backend)
+	ea.SetTxSource(&stubTxSource{txs: map[common.Address][]*types.Transaction{from: {tx0}}})
+
+	ea.packPending(b)
+
+	require.Empty(t, b.transactions, "a collected build must not receive any further txs")
+}
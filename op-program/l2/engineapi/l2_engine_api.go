@@ -7,12 +7,18 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sync"
 	"time"
 
+	"github.com/hashicorp/golang-lru/v2"
+
+	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/beacon/engine"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -24,6 +30,10 @@ import (
 	"github.com/ethereum-optimism/optimism/op-node/eth"
 )
 
+// invalidBlockHitsLimit bounds the invalid-ancestor cache, mirroring the size
+// upstream go-ethereum's catalyst API uses to remember recently rejected blocks.
+const invalidBlockHitsLimit = 512
+
 type EngineBackend interface {
 	CurrentBlock() *types.Header
 	CurrentSafeBlock() *types.Header
@@ -47,6 +57,13 @@ type EngineBackend interface {
 	SetCanonical(head *types.Block) (common.Hash, error)
 	SetFinalized(header *types.Header)
 	SetSafe(header *types.Header)
+
+	// BeaconSync triggers a reverse-header download towards head, so the node can catch up to a
+	// beacon-advertised chain tip it does not have locally yet.
+	BeaconSync(head common.Hash) error
+	// SyncProgress reports the status of any sync triggered by BeaconSync, or nil if the node is
+	// not currently syncing.
+	SyncProgress() *ethereum.SyncProgress
 }
 
 // L2EngineAPI wraps an engine actor, and implements the RPC backend required to serve the engine API.
@@ -56,23 +73,46 @@ type L2EngineAPI struct {
 	log     log.Logger
 	backend EngineBackend
 
-	// L2 block building data
-	l2BuildingHeader *types.Header             // block header that we add txs to for block building
-	l2BuildingState  *state.StateDB            // state used for block building
-	l2GasPool        *core.GasPool             // track gas used of ongoing building
-	pendingIndices   map[common.Address]uint64 // per account, how many txs from the pool were already included in the block, since the pool is lagging behind block mining.
-	l2Transactions   []*types.Transaction      // collects txs that were successfully included into current block build
-	l2Receipts       []*types.Receipt          // collect receipts of ongoing building
-	l2ForceEmpty     bool                      // when no additional txs may be processed (i.e. when sequencer drift runs out)
-	l2TxFailed       []*types.Transaction      // log of failed transactions which could not be included
-
-	payloadID engine.PayloadID // ID of payload that is currently being built
+	// mu guards building and pendingSync below. Before the JSON-RPC transport added in chunk0-3,
+	// this API was only ever driven by a single in-process actor goroutine, so these two fields
+	// were left unguarded; now that Serve exposes these methods to concurrent RPC connections,
+	// they need the same treatment blockBuilder's own fields already get from blockBuilder.mu.
+	mu sync.Mutex
+
+	// building is the build most recently started by ForkchoiceUpdated, i.e. the one that
+	// IncludeTx (driven by the sequencer actor) and the improve-payload loop append txs to. It is
+	// also reachable via payloads, which is what makes it safe for a later ForkchoiceUpdated to
+	// replace it here without losing an earlier, not-yet-collected build.
+	building *blockBuilder
+	payloads *payloadQueue // bounded ring of builds (building or already finished), keyed by PayloadID
+	headers  *headerQueue  // bounded ring of recently invalidated headers
+
+	// txSource, if set, feeds the background improve-payload loop; see SetTxSource.
+	txSource TxSource
+
+	// invalidBlocksHits tracks blocks that were rejected as invalid, keyed by the rejected
+	// block hash, mapping to the latest valid ancestor known at the time of rejection. This
+	// lets later NewPayload calls whose parent is a known-bad block fail fast instead of
+	// re-processing it. ForkchoiceUpdated does not consult it.
+	invalidBlocksHits *lru.Cache[common.Hash, common.Hash]
+
+	// lastTransitionConfigurationWarn throttles the mismatch warning logged by
+	// ExchangeTransitionConfigurationV1 so a CL that keeps sending a stale TTD doesn't spam the log.
+	lastTransitionConfigurationWarn time.Time
+
+	// pendingSync tracks a beacon sync triggered by ForkchoiceUpdated towards a head this engine
+	// doesn't have locally yet; see triggerBeaconSync.
+	pendingSync *beaconSync
 }
 
 func NewL2EngineAPI(log log.Logger, backend EngineBackend) *L2EngineAPI {
+	invalidBlocksHits, _ := lru.New[common.Hash, common.Hash](invalidBlockHitsLimit)
 	return &L2EngineAPI{
-		log:     log,
-		backend: backend,
+		log:               log,
+		backend:           backend,
+		payloads:          newPayloadQueue(),
+		headers:           newHeaderQueue(),
+		invalidBlocksHits: invalidBlocksHits,
 	}
 }
 
@@ -81,6 +121,18 @@ var (
 	STATUS_SYNCING = &eth.ForkchoiceUpdatedResult{PayloadStatus: eth.PayloadStatusV1{Status: eth.ExecutionSyncing}, PayloadID: nil}
 )
 
+// ChainID returns the execution chain's configured chain ID, for external packages (e.g. an RPC
+// server) that need to expose it without reaching into the unexported backend field directly.
+func (ea *L2EngineAPI) ChainID() *big.Int {
+	return ea.backend.Config().ChainID
+}
+
+// CurrentBlockNumber returns the execution chain's current block number, for external packages
+// (e.g. an RPC server) that need to expose it without reaching into the unexported backend field.
+func (ea *L2EngineAPI) CurrentBlockNumber() uint64 {
+	return ea.backend.CurrentBlock().Number.Uint64()
+}
+
 // computePayloadId computes a pseudo-random payloadid, based on the parameters.
 func computePayloadId(headBlockHash common.Hash, params *eth.PayloadAttributes) engine.PayloadID {
 	// Hash
@@ -96,71 +148,151 @@ func computePayloadId(headBlockHash common.Hash, params *eth.PayloadAttributes)
 		hasher.Write(tx)
 	}
 	_ = binary.Write(hasher, binary.BigEndian, *params.GasLimit)
+	if params.Withdrawals != nil {
+		for _, w := range *params.Withdrawals {
+			_ = binary.Write(hasher, binary.BigEndian, w.Index)
+			_ = binary.Write(hasher, binary.BigEndian, w.Validator)
+			hasher.Write(w.Address[:])
+			_ = binary.Write(hasher, binary.BigEndian, w.Amount)
+		}
+	}
+	if params.ParentBeaconBlockRoot != nil {
+		hasher.Write(params.ParentBeaconBlockRoot[:])
+	}
 	var out engine.PayloadID
 	copy(out[:], hasher.Sum(nil)[:8])
 	return out
 }
 
+// getBuilding returns the build most recently started by ForkchoiceUpdated, guarded against a
+// concurrent ForkchoiceUpdated/collectPayload call replacing or clearing it.
+func (ea *L2EngineAPI) getBuilding() *blockBuilder {
+	ea.mu.Lock()
+	defer ea.mu.Unlock()
+	return ea.building
+}
+
 func (ea *L2EngineAPI) RemainingBlockGas() uint64 {
-	return ea.l2GasPool.Gas()
+	b := ea.getBuilding()
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.gasPool.Gas()
 }
 
 func (ea *L2EngineAPI) ForcedEmpty() bool {
-	return ea.l2ForceEmpty
+	b := ea.getBuilding()
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.forceEmpty
 }
 
 func (ea *L2EngineAPI) PendingIndices(from common.Address) uint64 {
-	return ea.pendingIndices[from]
+	b := ea.getBuilding()
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pendingIndices[from]
 }
 
 var (
 	ErrNotBuildingBlock = errors.New("not currently building a block, cannot include tx from queue")
 	ErrExceedsGasLimit  = errors.New("tx gas exceeds block gas limit")
 	ErrUsesTooMuchGas   = errors.New("action takes too much gas")
+	ErrBlobTxNotAllowed = errors.New("blob transactions not allowed before Cancun activation")
+	ErrAlreadySealed    = errors.New("build has already been collected, cannot include more txs")
 )
 
+// IncludeTx includes tx, authored by from, into the build currently receiving txs (i.e. the one
+// most recently started by ForkchoiceUpdated).
 func (ea *L2EngineAPI) IncludeTx(tx *types.Transaction, from common.Address) error {
-	if ea.l2BuildingHeader == nil {
+	b := ea.getBuilding()
+	if b == nil {
 		return ErrNotBuildingBlock
 	}
-	if ea.l2ForceEmpty {
+	return ea.includeTx(b, tx, from)
+}
+
+func (ea *L2EngineAPI) includeTx(b *blockBuilder, tx *types.Transaction, from common.Address) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.collected {
+		return ErrAlreadySealed
+	}
+	if b.forceEmpty {
 		ea.log.Info("Skipping including a transaction because e.L2ForceEmpty is true")
 		// t.InvalidAction("cannot include any sequencer txs")
 		return nil
 	}
+	if tx.Type() == types.BlobTxType && b.header.ExcessBlobGas == nil {
+		return ErrBlobTxNotAllowed
+	}
 
-	if tx.Gas() > ea.l2BuildingHeader.GasLimit {
-		return fmt.Errorf("%w tx gas: %d, block gas limit: %d", ErrExceedsGasLimit, tx.Gas(), ea.l2BuildingHeader.GasLimit)
+	if tx.Gas() > b.header.GasLimit {
+		return fmt.Errorf("%w tx gas: %d, block gas limit: %d", ErrExceedsGasLimit, tx.Gas(), b.header.GasLimit)
 	}
-	if tx.Gas() > uint64(*ea.l2GasPool) {
-		return fmt.Errorf("%w: %d, only have %d", ErrUsesTooMuchGas, tx.Gas(), uint64(*ea.l2GasPool))
+	if tx.Gas() > uint64(*b.gasPool) {
+		return fmt.Errorf("%w: %d, only have %d", ErrUsesTooMuchGas, tx.Gas(), uint64(*b.gasPool))
 	}
 
-	ea.pendingIndices[from] = ea.pendingIndices[from] + 1 // won't retry the tx
-	ea.l2BuildingState.SetTxContext(tx.Hash(), len(ea.l2Transactions))
-	receipt, err := core.ApplyTransaction(ea.backend.Config(), ea.backend, &ea.l2BuildingHeader.Coinbase,
-		ea.l2GasPool, ea.l2BuildingState, ea.l2BuildingHeader, tx, &ea.l2BuildingHeader.GasUsed, *ea.backend.GetVMConfig())
+	b.pendingIndices[from] = b.pendingIndices[from] + 1 // won't retry the tx
+	b.state.SetTxContext(tx.Hash(), len(b.transactions))
+	receipt, err := core.ApplyTransaction(ea.backend.Config(), ea.backend, &b.header.Coinbase,
+		b.gasPool, b.state, b.header, tx, &b.header.GasUsed, *ea.backend.GetVMConfig())
 	if err != nil {
-		ea.l2TxFailed = append(ea.l2TxFailed, tx)
-		return fmt.Errorf("invalid L2 block (tx %d): %w", len(ea.l2Transactions), err)
+		b.txFailed = append(b.txFailed, tx)
+		return fmt.Errorf("invalid L2 block (tx %d): %w", len(b.transactions), err)
+	}
+	if b.header.BlobGasUsed != nil {
+		*b.header.BlobGasUsed += tx.BlobGas()
 	}
-	ea.l2Receipts = append(ea.l2Receipts, receipt)
-	ea.l2Transactions = append(ea.l2Transactions, tx)
+	b.receipts = append(b.receipts, receipt)
+	b.transactions = append(b.transactions, tx)
 	return nil
 }
 
-func (ea *L2EngineAPI) startBlock(parent common.Hash, params *eth.PayloadAttributes) error {
-	if ea.l2BuildingHeader != nil {
-		ea.log.Warn("started building new block without ending previous block", "previous", ea.l2BuildingHeader, "prev_payload_id", ea.payloadID)
+// startBlock begins a new build on top of parent and returns it directly to the caller, rather
+// than making the caller re-read ea.building afterward: under the concurrent RPC transport, a
+// second ForkchoiceUpdated-with-attributes call can race in and replace ea.building before the
+// first caller gets a chance to look it back up, which would hand the first caller back the
+// second call's payload ID.
+func (ea *L2EngineAPI) startBlock(parent common.Hash, params *eth.PayloadAttributes) (*blockBuilder, error) {
+	if prev := ea.getBuilding(); prev != nil {
+		ea.log.Warn("started building new block without ending previous block", "previous", prev.header, "prev_payload_id", prev.id)
 	}
 
 	parentHeader := ea.backend.GetHeaderByHash(parent)
 	if parentHeader == nil {
-		return fmt.Errorf("uknown parent block: %s", parent)
+		return nil, fmt.Errorf("uknown parent block: %s", parent)
 	}
 	statedb, err := ea.backend.StateAt(parentHeader.Root)
 	if err != nil {
-		return fmt.Errorf("failed to init state db around block %s (state %s): %w", parent, parentHeader.Root, err)
+		return nil, fmt.Errorf("failed to init state db around block %s (state %s): %w", parent, parentHeader.Root, err)
+	}
+
+	config := ea.backend.Config()
+	timestamp := uint64(params.Timestamp)
+	isShanghai := config.IsShanghai(new(big.Int).Add(parentHeader.Number, common.Big1), timestamp)
+	isCancun := config.IsCancun(new(big.Int).Add(parentHeader.Number, common.Big1), timestamp)
+
+	if params.Withdrawals != nil && !isShanghai {
+		return nil, fmt.Errorf("withdrawals in payload attributes before Shanghai activation at time %d", timestamp)
+	}
+	if params.Withdrawals == nil && isShanghai {
+		return nil, fmt.Errorf("missing withdrawals in payload attributes on and after Shanghai activation at time %d", timestamp)
+	}
+	if params.ParentBeaconBlockRoot == nil && isCancun {
+		return nil, fmt.Errorf("missing parent beacon block root in payload attributes on and after Cancun activation at time %d", timestamp)
+	}
+	if params.ParentBeaconBlockRoot != nil && !isCancun {
+		return nil, fmt.Errorf("parent beacon block root in payload attributes before Cancun activation at time %d", timestamp)
 	}
 
 	header := &types.Header{
@@ -176,62 +308,196 @@ func (ea *L2EngineAPI) startBlock(parent common.Hash, params *eth.PayloadAttribu
 
 	header.BaseFee = misc.CalcBaseFee(ea.backend.Config(), parentHeader)
 
-	ea.l2BuildingHeader = header
-	ea.l2BuildingState = statedb
-	ea.l2Receipts = make([]*types.Receipt, 0)
-	ea.l2Transactions = make([]*types.Transaction, 0)
-	ea.pendingIndices = make(map[common.Address]uint64)
-	ea.l2ForceEmpty = params.NoTxPool
-	ea.l2GasPool = new(core.GasPool).AddGas(header.GasLimit)
-	ea.payloadID = computePayloadId(parent, params)
+	b := &blockBuilder{
+		id:             computePayloadId(parent, params),
+		header:         header,
+		state:          statedb,
+		receipts:       make([]*types.Receipt, 0),
+		transactions:   make([]*types.Transaction, 0),
+		pendingIndices: make(map[common.Address]uint64),
+		forceEmpty:     params.NoTxPool,
+		gasPool:        new(core.GasPool).AddGas(header.GasLimit),
+		done:           make(chan struct{}),
+	}
+	if isShanghai {
+		b.withdrawals = make(types.Withdrawals, len(*params.Withdrawals))
+		copy(b.withdrawals, *params.Withdrawals)
+	}
+	header.ParentBeaconRoot = params.ParentBeaconBlockRoot
+	if isCancun {
+		var parentExcessBlobGas, parentBlobGasUsed uint64
+		if parentHeader.ExcessBlobGas != nil {
+			parentExcessBlobGas = *parentHeader.ExcessBlobGas
+		}
+		if parentHeader.BlobGasUsed != nil {
+			parentBlobGasUsed = *parentHeader.BlobGasUsed
+		}
+		excessBlobGas := eip4844.CalcExcessBlobGas(parentExcessBlobGas, parentBlobGasUsed)
+		header.ExcessBlobGas = &excessBlobGas
+		header.BlobGasUsed = new(uint64)
+
+		// EIP-4788: write the parent beacon block root into the beacon-roots contract before
+		// applying any transactions, matching what miner/worker does for a Cancun block. Without
+		// this, the block's computed state root would diverge from NewPayloadV3's validation.
+		vmContext := core.NewEVMBlockContext(header, ea.backend, nil)
+		vmenv := vm.NewEVM(vmContext, vm.TxContext{}, b.state, config, *ea.backend.GetVMConfig())
+		core.ProcessBeaconBlockRoot(*header.ParentBeaconRoot, vmenv, b.state)
+	}
 
 	// pre-process the deposits
 	for i, otx := range params.Transactions {
 		var tx types.Transaction
 		if err := tx.UnmarshalBinary(otx); err != nil {
-			return fmt.Errorf("transaction %d is not valid: %w", i, err)
+			return nil, fmt.Errorf("transaction %d is not valid: %w", i, err)
 		}
-		ea.l2BuildingState.SetTxContext(tx.Hash(), i)
-		receipt, err := core.ApplyTransaction(ea.backend.Config(), ea.backend, &ea.l2BuildingHeader.Coinbase,
-			ea.l2GasPool, ea.l2BuildingState, ea.l2BuildingHeader, &tx, &ea.l2BuildingHeader.GasUsed, *ea.backend.GetVMConfig())
+		b.state.SetTxContext(tx.Hash(), i)
+		receipt, err := core.ApplyTransaction(ea.backend.Config(), ea.backend, &b.header.Coinbase,
+			b.gasPool, b.state, b.header, &tx, &b.header.GasUsed, *ea.backend.GetVMConfig())
 		if err != nil {
-			ea.l2TxFailed = append(ea.l2TxFailed, &tx)
-			return fmt.Errorf("failed to apply deposit transaction to L2 block (tx %d): %w", i, err)
+			b.txFailed = append(b.txFailed, &tx)
+			return nil, fmt.Errorf("failed to apply deposit transaction to L2 block (tx %d): %w", i, err)
 		}
-		ea.l2Receipts = append(ea.l2Receipts, receipt)
-		ea.l2Transactions = append(ea.l2Transactions, &tx)
+		if b.header.BlobGasUsed != nil {
+			*b.header.BlobGasUsed += tx.BlobGas()
+		}
+		b.receipts = append(b.receipts, receipt)
+		b.transactions = append(b.transactions, &tx)
 	}
-	return nil
+
+	ea.mu.Lock()
+	ea.building = b
+	ea.mu.Unlock()
+	ea.payloads.put(b)
+	go ea.improvePayload(b)
+	return b, nil
 }
 
-func (ea *L2EngineAPI) endBlock() (*types.Block, error) {
-	if ea.l2BuildingHeader == nil {
-		return nil, fmt.Errorf("no block is being built currently (id %s)", ea.payloadID)
+func endBlock(backend EngineBackend, b *blockBuilder) (*types.Block, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	header := b.header
+	header.GasUsed = header.GasLimit - uint64(*b.gasPool)
+	header.Root = b.state.IntermediateRoot(backend.Config().IsEIP158(header.Number))
+	if b.withdrawals != nil {
+		withdrawalsHash := types.DeriveSha(b.withdrawals, trie.NewStackTrie(nil))
+		header.WithdrawalsHash = &withdrawalsHash
 	}
-	header := ea.l2BuildingHeader
-	ea.l2BuildingHeader = nil
-
-	header.GasUsed = header.GasLimit - uint64(*ea.l2GasPool)
-	header.Root = ea.l2BuildingState.IntermediateRoot(ea.backend.Config().IsEIP158(header.Number))
-	block := types.NewBlock(header, ea.l2Transactions, nil, ea.l2Receipts, trie.NewStackTrie(nil))
+	block := types.NewBlock(header, b.transactions, nil, b.receipts, trie.NewStackTrie(nil))
+	block = block.WithWithdrawals(b.withdrawals)
 	return block, nil
 }
 
+// collectPayload stops the improve-payload loop for the build with the given ID and finalizes its
+// block, looking the build up in the payload queue rather than assuming it is the one currently
+// receiving IncludeTx calls, so an earlier, not-yet-collected build survives a later
+// ForkchoiceUpdated that replaces ea.building.
+func (ea *L2EngineAPI) collectPayload(payloadId eth.PayloadID) (*types.Block, error) {
+	b := ea.payloads.get(engine.PayloadID(payloadId))
+	if b == nil {
+		return nil, fmt.Errorf("unknown payload %s", payloadId)
+	}
+	b.close()
+	ea.mu.Lock()
+	if ea.building == b {
+		ea.building = nil
+	}
+	ea.mu.Unlock()
+	return endBlock(ea.backend, b)
+}
+
 func (ea *L2EngineAPI) GetPayloadV1(ctx context.Context, payloadId eth.PayloadID) (*eth.ExecutionPayload, error) {
 	ea.log.Trace("L2Engine API request received", "method", "GetPayload", "id", payloadId)
-	if ea.payloadID != payloadId {
-		ea.log.Warn("unexpected payload ID requested for block building", "expected", ea.payloadID, "got", payloadId)
+	bl, err := ea.collectPayload(payloadId)
+	if err != nil {
+		ea.log.Error("failed to finish block building", "err", err)
 		return nil, engine.UnknownPayload
 	}
-	bl, err := ea.endBlock()
+	return eth.BlockAsPayload(bl)
+}
+
+// GetPayloadV2 returns the Shanghai-era payload (including withdrawals) for the given payload ID.
+func (ea *L2EngineAPI) GetPayloadV2(ctx context.Context, payloadId eth.PayloadID) (*eth.ExecutionPayloadEnvelope, error) {
+	ea.log.Trace("L2Engine API request received", "method", "GetPayloadV2", "id", payloadId)
+	bl, err := ea.collectPayload(payloadId)
 	if err != nil {
 		ea.log.Error("failed to finish block building", "err", err)
 		return nil, engine.UnknownPayload
 	}
-	return eth.BlockAsPayload(bl)
+	return eth.BlockAsPayloadEnv(bl, ea.backend.Config())
+}
+
+// GetPayloadV3 returns the Cancun-era payload (including blob-bundle info) for the given payload ID.
+func (ea *L2EngineAPI) GetPayloadV3(ctx context.Context, payloadId eth.PayloadID) (*eth.ExecutionPayloadEnvelope, error) {
+	ea.log.Trace("L2Engine API request received", "method", "GetPayloadV3", "id", payloadId)
+	bl, err := ea.collectPayload(payloadId)
+	if err != nil {
+		ea.log.Error("failed to finish block building", "err", err)
+		return nil, engine.UnknownPayload
+	}
+	if !ea.backend.Config().IsCancun(bl.Number(), bl.Time()) {
+		return nil, engine.UnsupportedFork.With(errors.New("GetPayloadV3 called pre-Cancun"))
+	}
+	return eth.BlockAsPayloadEnv(bl, ea.backend.Config())
 }
 
 func (ea *L2EngineAPI) ForkchoiceUpdatedV1(ctx context.Context, state *eth.ForkchoiceState, attr *eth.PayloadAttributes) (*eth.ForkchoiceUpdatedResult, error) {
+	if attr != nil && attr.Withdrawals != nil {
+		return STATUS_INVALID, engine.InvalidPayloadAttributes.With(errors.New("withdrawals not supported in V1"))
+	}
+	return ea.forkchoiceUpdated(ctx, state, attr)
+}
+
+// ForkchoiceUpdatedV2 additionally allows Shanghai withdrawals in the payload attributes.
+func (ea *L2EngineAPI) ForkchoiceUpdatedV2(ctx context.Context, state *eth.ForkchoiceState, attr *eth.PayloadAttributes) (*eth.ForkchoiceUpdatedResult, error) {
+	if attr != nil && attr.ParentBeaconBlockRoot != nil {
+		return STATUS_INVALID, engine.InvalidPayloadAttributes.With(errors.New("parent beacon block root not supported before V3"))
+	}
+	return ea.forkchoiceUpdated(ctx, state, attr)
+}
+
+// ForkchoiceUpdatedV3 additionally allows Cancun parentBeaconBlockRoot in the payload attributes.
+func (ea *L2EngineAPI) ForkchoiceUpdatedV3(ctx context.Context, state *eth.ForkchoiceState, attr *eth.PayloadAttributes) (*eth.ForkchoiceUpdatedResult, error) {
+	return ea.forkchoiceUpdated(ctx, state, attr)
+}
+
+// transitionConfigurationWarnInterval bounds how often a mismatching TTD is re-logged, so a CL
+// client that polls this endpoint every slot with a stale value doesn't flood the logs.
+const transitionConfigurationWarnInterval = time.Minute
+
+// ExchangeTransitionConfigurationV1 lets a consensus client double check that its view of the
+// merge transition (terminalTotalDifficulty/terminalBlockHash/terminalBlockNumber) matches this
+// execution engine's. OP Stack chains launch post-merge, but standard CL tooling and conformance
+// suites still call this on startup, so it needs to be served rather than left unimplemented.
+func (ea *L2EngineAPI) ExchangeTransitionConfigurationV1(ctx context.Context, cfg engine.TransitionConfigurationV1) (*engine.TransitionConfigurationV1, error) {
+	if cfg.TerminalTotalDifficulty == nil {
+		return nil, errors.New("invalid terminal total difficulty")
+	}
+	ttd := ea.backend.Config().TerminalTotalDifficulty
+	if ttd == nil || ttd.Cmp(cfg.TerminalTotalDifficulty.ToInt()) != 0 {
+		if time.Since(ea.lastTransitionConfigurationWarn) > transitionConfigurationWarnInterval {
+			ea.log.Warn("Invalid terminal total difficulty configured", "execution", ttd, "consensus", cfg.TerminalTotalDifficulty)
+			ea.lastTransitionConfigurationWarn = time.Now()
+		}
+		return nil, fmt.Errorf("invalid ttd: execution %v consensus %v", ttd, cfg.TerminalTotalDifficulty)
+	}
+	if cfg.TerminalBlockHash != (common.Hash{}) {
+		terminalHeader := ea.backend.GetHeaderByHash(cfg.TerminalBlockHash)
+		if terminalHeader == nil {
+			return nil, fmt.Errorf("could not find terminal block %s", cfg.TerminalBlockHash)
+		}
+		if terminalHeader.Number.Uint64() != uint64(cfg.TerminalBlockNumber) {
+			return nil, fmt.Errorf("invalid terminal block number: execution %d consensus %d", terminalHeader.Number, cfg.TerminalBlockNumber)
+		}
+		return &engine.TransitionConfigurationV1{
+			TerminalTotalDifficulty: (*hexutil.Big)(ttd),
+			TerminalBlockHash:       cfg.TerminalBlockHash,
+			TerminalBlockNumber:     cfg.TerminalBlockNumber,
+		}, nil
+	}
+	return &engine.TransitionConfigurationV1{TerminalTotalDifficulty: (*hexutil.Big)(ttd)}, nil
+}
+
+func (ea *L2EngineAPI) forkchoiceUpdated(ctx context.Context, state *eth.ForkchoiceState, attr *eth.PayloadAttributes) (*eth.ForkchoiceUpdatedResult, error) {
 	ea.log.Trace("L2Engine API request received", "method", "ForkchoiceUpdated", "head", state.HeadBlockHash, "finalized", state.FinalizedBlockHash, "safe", state.SafeBlockHash)
 	if state.HeadBlockHash == (common.Hash{}) {
 		ea.log.Warn("Forkchoice requested update to zero hash")
@@ -242,9 +508,13 @@ func (ea *L2EngineAPI) ForkchoiceUpdatedV1(ctx context.Context, state *eth.Forkc
 	// reason.
 	block := ea.backend.GetBlockByHash(state.HeadBlockHash)
 	if block == nil {
-		// TODO: syncing not supported yet
-		return STATUS_SYNCING, nil
+		return ea.triggerBeaconSync(state.HeadBlockHash)
 	}
+	// The advertised head resolved locally; forget any sync that was pending towards it (or an
+	// earlier head we've since moved past).
+	ea.mu.Lock()
+	ea.pendingSync = nil
+	ea.mu.Unlock()
 	// Block is known locally, just sanity check that the beacon client does not
 	// attempt to push us back to before the merge.
 	// Note: Differs from op-geth implementation as pre-merge blocks are never supported here
@@ -303,18 +573,46 @@ func (ea *L2EngineAPI) ForkchoiceUpdatedV1(ctx context.Context, state *eth.Forkc
 	// sealed by the beacon client. The payload will be requested later, and we
 	// might replace it arbitrarily many times in between.
 	if attr != nil {
-		err := ea.startBlock(state.HeadBlockHash, attr)
+		b, err := ea.startBlock(state.HeadBlockHash, attr)
 		if err != nil {
 			ea.log.Error("Failed to start block building", "err", err, "noTxPool", attr.NoTxPool, "txs", len(attr.Transactions), "timestamp", attr.Timestamp)
 			return STATUS_INVALID, engine.InvalidPayloadAttributes.With(err)
 		}
 
-		return valid(&ea.payloadID), nil
+		id := b.id
+		return valid(&id), nil
 	}
 	return valid(nil), nil
 }
 
 func (ea *L2EngineAPI) NewPayloadV1(ctx context.Context, payload *eth.ExecutionPayload) (*eth.PayloadStatusV1, error) {
+	if payload.Withdrawals != nil {
+		return &eth.PayloadStatusV1{Status: eth.ExecutionInvalid}, errors.New("withdrawals not supported in V1")
+	}
+	return ea.newPayload(ctx, payload, nil, nil)
+}
+
+// NewPayloadV2 additionally accepts a Shanghai payload carrying withdrawals.
+func (ea *L2EngineAPI) NewPayloadV2(ctx context.Context, payload *eth.ExecutionPayload) (*eth.PayloadStatusV1, error) {
+	if payload.BlobGasUsed != nil || payload.ExcessBlobGas != nil {
+		return &eth.PayloadStatusV1{Status: eth.ExecutionInvalid}, errors.New("blob gas fields not supported before V3")
+	}
+	return ea.newPayload(ctx, payload, nil, nil)
+}
+
+// NewPayloadV3 additionally accepts the blob versioned hashes and parent beacon block root
+// required to validate a Cancun payload that may carry blob-bearing transactions.
+func (ea *L2EngineAPI) NewPayloadV3(ctx context.Context, payload *eth.ExecutionPayload, versionedHashes []common.Hash, parentBeaconBlockRoot *common.Hash) (*eth.PayloadStatusV1, error) {
+	if payload.BlobGasUsed == nil || payload.ExcessBlobGas == nil {
+		return &eth.PayloadStatusV1{Status: eth.ExecutionInvalid}, errors.New("missing blob gas fields in V3 payload")
+	}
+	if parentBeaconBlockRoot == nil {
+		return &eth.PayloadStatusV1{Status: eth.ExecutionInvalid}, errors.New("missing parent beacon block root in V3 payload")
+	}
+	return ea.newPayload(ctx, payload, versionedHashes, parentBeaconBlockRoot)
+}
+
+func (ea *L2EngineAPI) newPayload(ctx context.Context, payload *eth.ExecutionPayload, versionedHashes []common.Hash, parentBeaconBlockRoot *common.Hash) (*eth.PayloadStatusV1, error) {
 	ea.log.Trace("L2Engine API request received", "method", "ExecutePayload", "number", payload.BlockNumber, "hash", payload.BlockHash)
 	txs := make([][]byte, len(payload.Transactions))
 	for i, tx := range payload.Transactions {
@@ -335,7 +633,10 @@ func (ea *L2EngineAPI) NewPayloadV1(ctx context.Context, payload *eth.ExecutionP
 		BaseFeePerGas: payload.BaseFeePerGas.ToBig(),
 		BlockHash:     payload.BlockHash,
 		Transactions:  txs,
-	})
+		Withdrawals:   payload.Withdrawals,
+		BlobGasUsed:   (*uint64)(payload.BlobGasUsed),
+		ExcessBlobGas: (*uint64)(payload.ExcessBlobGas),
+	}, versionedHashes, parentBeaconBlockRoot)
 	if err != nil {
 		log.Debug("Invalid NewPayload params", "params", payload, "error", err)
 		return &eth.PayloadStatusV1{Status: eth.ExecutionInvalidBlockHash}, nil
@@ -348,17 +649,34 @@ func (ea *L2EngineAPI) NewPayloadV1(ctx context.Context, payload *eth.ExecutionP
 		return &eth.PayloadStatusV1{Status: eth.ExecutionValid, LatestValidHash: &hash}, nil
 	}
 
-	// TODO: skipping invalid ancestor check (i.e. not remembering previously failed blocks)
+	// If the parent of this payload is already known to be invalid, don't bother
+	// processing it again: immediately propagate the earliest known-valid ancestor.
+	if lvh, ok := ea.invalidBlocksHits.Get(block.ParentHash()); ok {
+		ea.log.Warn("Rejecting payload with known invalid ancestor", "hash", block.Hash(), "parent", block.ParentHash())
+		ea.setInvalidAncestor(block.Header(), lvh)
+		errorMsg := fmt.Sprintf("links to previously rejected block %s", block.ParentHash())
+		return &eth.PayloadStatusV1{Status: eth.ExecutionInvalid, LatestValidHash: &lvh, ValidationError: &errorMsg}, nil
+	}
 
 	parent := ea.backend.GetBlock(block.ParentHash(), block.NumberU64()-1)
 	if parent == nil {
-		// TODO: hack, saying we accepted if we don't know the parent block. Might want to return critical error if we can't actually sync.
+		// We don't know the parent block, so we can't execute this payload yet. Kick off a reverse
+		// header sync towards it - deduped/refreshed against any sync already pending the same way
+		// ForkchoiceUpdated does it, so a CL repeatedly resubmitting this NewPayload while we catch
+		// up doesn't retrigger BeaconSync on every call - and tell the CL we've accepted the payload
+		// for now, matching upstream eth/catalyst's handling of a NewPayload call that references an
+		// unknown ancestor.
+		if err := ea.maybeTriggerBeaconSync(block.ParentHash()); err != nil {
+			ea.log.Warn("Failed to trigger beacon sync for unknown payload parent", "parent", block.ParentHash(), "err", err)
+		}
 		return &eth.PayloadStatusV1{Status: eth.ExecutionAccepted, LatestValidHash: nil}, nil
 	}
 
 	if block.Time() <= parent.Time() {
 		log.Warn("Invalid timestamp", "parent", block.Time(), "block", block.Time())
-		return ea.invalid(errors.New("invalid timestamp"), parent.Header()), nil
+		status := ea.invalid(errors.New("invalid timestamp"), parent.Header())
+		ea.setInvalidAncestor(block.Header(), *status.LatestValidHash)
+		return status, nil
 	}
 
 	if !ea.backend.HasBlockAndState(block.ParentHash(), block.NumberU64()-1) {
@@ -367,14 +685,30 @@ func (ea *L2EngineAPI) NewPayloadV1(ctx context.Context, payload *eth.ExecutionP
 	}
 	log.Trace("Inserting block without sethead", "hash", block.Hash(), "number", block.Number)
 	if err := ea.backend.InsertBlockWithoutSetHead(block); err != nil {
-		ea.log.Warn("NewPayloadV1: inserting block failed", "error", err)
-		// TODO not remembering the payload as invalid
-		return ea.invalid(err, parent.Header()), nil
+		ea.log.Warn("NewPayload: inserting block failed", "error", err)
+		status := ea.invalid(err, parent.Header())
+		ea.setInvalidAncestor(block.Header(), *status.LatestValidHash)
+		return status, nil
 	}
 	hash := block.Hash()
 	return &eth.PayloadStatusV1{Status: eth.ExecutionValid, LatestValidHash: &hash}, nil
 }
 
+// setInvalidAncestor records that header is invalid, remembering the earliest known-valid
+// ancestor so that later payloads descending from it can be rejected without re-execution. The
+// header itself is also kept in the header queue, since it was never inserted into the backend's
+// database and would otherwise be unobtainable by hash once the rejecting call returns.
+func (ea *L2EngineAPI) setInvalidAncestor(header *types.Header, latestValidHash common.Hash) {
+	ea.invalidBlocksHits.Add(header.Hash(), latestValidHash)
+	ea.headers.put(header)
+}
+
+// InvalidHeader returns the header of a block that was previously rejected by NewPayload, if
+// still remembered, for tests and CLI tooling to inspect why a hash was marked invalid.
+func (ea *L2EngineAPI) InvalidHeader(hash common.Hash) *types.Header {
+	return ea.headers.get(hash)
+}
+
 func (ea *L2EngineAPI) invalid(err error, latestValid *types.Header) *eth.PayloadStatusV1 {
 	currentHash := ea.backend.CurrentBlock().Hash()
 	if latestValid != nil {
@@ -383,8 +717,13 @@ func (ea *L2EngineAPI) invalid(err error, latestValid *types.Header) *eth.Payloa
 		if latestValid.Difficulty.BitLen() == 0 {
 			// Otherwise set latest valid hash to parent hash
 			currentHash = latestValid.Hash()
+			// If the parent itself was already marked invalid, propagate the earliest
+			// known-valid ancestor rather than pointing at another invalid block.
+			if lvh, ok := ea.invalidBlocksHits.Get(currentHash); ok {
+				currentHash = lvh
+			}
 		}
 	}
 	errorMsg := err.Error()
 	return &eth.PayloadStatusV1{Status: eth.ExecutionInvalid, LatestValidHash: &currentHash, ValidationError: &errorMsg}
-}
\ No newline at end of file
+}
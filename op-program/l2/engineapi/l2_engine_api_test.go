@@ -0,0 +1,48 @@
+package engineapi
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEngineAPI(t *testing.T, backend *fakeBackend) *L2EngineAPI {
+	t.Helper()
+	if backend.config == nil {
+		backend.config = &params.ChainConfig{}
+	}
+	return NewL2EngineAPI(log.New(), backend)
+}
+
+// TestInvalidAncestorPropagation covers the LatestValidHash propagation chain: a new payload whose
+// parent was itself already recorded as invalid (pointing at some earliest-known-valid ancestor)
+// must report that earliest ancestor, not the immediate parent that got rejected.
+func TestInvalidAncestorPropagation(t *testing.T) {
+	ea := newTestEngineAPI(t, &fakeBackend{})
+
+	earliestValid := &types.Header{Number: big.NewInt(1), Difficulty: common.Big0}
+	rejectedParent := &types.Header{Number: big.NewInt(2), ParentHash: earliestValid.Hash(), Difficulty: common.Big0}
+	ea.setInvalidAncestor(rejectedParent, earliestValid.Hash())
+
+	status := ea.invalid(errors.New("links to previously rejected block"), rejectedParent)
+
+	require.Equal(t, earliestValid.Hash(), *status.LatestValidHash)
+	require.Equal(t, rejectedParent, ea.InvalidHeader(rejectedParent.Hash()))
+}
+
+// TestInvalidAncestorPropagationStopsAtFirstValidHeader ensures a header that was never marked
+// invalid is reported as-is, without any propagation.
+func TestInvalidAncestorPropagationStopsAtFirstValidHeader(t *testing.T) {
+	ea := newTestEngineAPI(t, &fakeBackend{})
+
+	valid := &types.Header{Number: big.NewInt(1), Difficulty: common.Big0}
+	status := ea.invalid(errors.New("bad timestamp"), valid)
+
+	require.Equal(t, valid.Hash(), *status.LatestValidHash)
+}
@@ -0,0 +1,45 @@
+package engineapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/op-node/eth"
+)
+
+// TestNewPayloadShortCircuitsOnKnownInvalidAncestor drives the known-invalid-ancestor rejection in
+// newPayload through the public NewPayloadV1 entrypoint, rather than just the setInvalidAncestor/
+// invalid helpers directly: a payload whose parent was already recorded as invalid must be
+// rejected immediately, reporting the earliest known-valid ancestor.
+func TestNewPayloadShortCircuitsOnKnownInvalidAncestor(t *testing.T) {
+	backend := &fakeBackend{config: &params.ChainConfig{}}
+	ea := newTestEngineAPI(t, backend)
+
+	badParent := &types.Header{Number: big.NewInt(1), Difficulty: common.Big0}
+	earliestValid := common.HexToHash("0xbeef")
+	ea.setInvalidAncestor(badParent, earliestValid)
+
+	child := types.NewBlock(&types.Header{
+		ParentHash: badParent.Hash(),
+		Number:     big.NewInt(2),
+		GasLimit:   30_000_000,
+		Difficulty: common.Big0,
+		BaseFee:    big.NewInt(0),
+	}, nil, nil, nil, trie.NewStackTrie(nil))
+
+	payload, err := eth.BlockAsPayload(child)
+	require.NoError(t, err)
+
+	status, err := ea.NewPayloadV1(context.Background(), payload)
+	require.NoError(t, err)
+	require.Equal(t, eth.ExecutionInvalid, status.Status)
+	require.Equal(t, earliestValid, *status.LatestValidHash)
+	require.NotNil(t, ea.InvalidHeader(child.Hash()), "the rejected child itself must now be remembered as invalid too")
+}
@@ -0,0 +1,118 @@
+package engineapi
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// maxTrackedPayloads is the number of in-flight/finished builds the engine remembers at once,
+// matching the bound upstream eth/catalyst/queue.go uses for its payload and header queues.
+const maxTrackedPayloads = 10
+
+// blockBuilder holds all of the mutable state for a single in-progress (or finished) L2 block
+// build. Pulling this out of L2EngineAPI itself is what lets multiple builds be tracked
+// concurrently: previously a single set of these fields lived directly on L2EngineAPI, so a
+// second ForkchoiceUpdated-with-attributes would clobber whatever the first was building.
+type blockBuilder struct {
+	id engine.PayloadID
+
+	// mu guards every field below against concurrent access between the background
+	// improve-payload loop (see improve.go) and whichever goroutine calls IncludeTx or collects
+	// the finished block (see collectPayload/endBlock in l2_engine_api.go).
+	mu sync.Mutex
+
+	header         *types.Header             // block header that we add txs to for block building
+	state          *state.StateDB            // state used for block building
+	gasPool        *core.GasPool             // track gas used of ongoing building
+	pendingIndices map[common.Address]uint64 // per account, how many txs from the pool were already included
+	transactions   []*types.Transaction      // collects txs that were successfully included into current block build
+	receipts       []*types.Receipt          // collect receipts of ongoing building
+	txFailed       []*types.Transaction      // log of failed transactions which could not be included
+	withdrawals    types.Withdrawals         // Shanghai withdrawals to include in the block being built
+	forceEmpty     bool                      // when no additional txs may be processed (i.e. when sequencer drift runs out)
+	collected      bool                      // set once GetPayload has started sealing this build; blocks further tx inclusion
+
+	done chan struct{} // closed once GetPayload is called, to stop the background improve-payload loop
+	stop sync.Once
+}
+
+// close marks b as collected so the improve-payload loop stops including further txs, and closes
+// b.done to stop the loop itself. The collected flag is set under b.mu, which includeTx holds from
+// its collected check until the tx is applied, so an inclusion already in progress finishes before
+// close returns and any later one is rejected; b.done is closed after b.mu is released.
+func (b *blockBuilder) close() {
+	b.mu.Lock()
+	b.collected = true
+	b.mu.Unlock()
+	b.stop.Do(func() { close(b.done) })
+}
+
+// payloadQueue is a small bounded ring of payload builders keyed by PayloadID, as in upstream
+// eth/catalyst/queue.go. It lets GetPayload look a specific build up by ID instead of comparing
+// against a single "current" field, so an older, not-yet-collected build survives a newer
+// ForkchoiceUpdated call.
+type payloadQueue struct {
+	mu    sync.Mutex
+	items [maxTrackedPayloads]*blockBuilder
+}
+
+func newPayloadQueue() *payloadQueue {
+	return &payloadQueue{}
+}
+
+// put pushes b onto the front of the ring, evicting (and closing) the oldest entry if full.
+func (q *payloadQueue) put(b *blockBuilder) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if evicted := q.items[len(q.items)-1]; evicted != nil {
+		evicted.close()
+	}
+	copy(q.items[1:], q.items[:len(q.items)-1])
+	q.items[0] = b
+}
+
+func (q *payloadQueue) get(id engine.PayloadID) *blockBuilder {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, b := range q.items {
+		if b != nil && b.id == id {
+			return b
+		}
+	}
+	return nil
+}
+
+// headerQueue remembers the most recently invalidated headers, as in upstream
+// eth/catalyst/queue.go, so that InvalidHeader can still return one of them by hash after the
+// NewPayload call that rejected it has returned. NewPayload itself does not read from it.
+type headerQueue struct {
+	mu    sync.Mutex
+	items [maxTrackedPayloads]*types.Header
+}
+
+func newHeaderQueue() *headerQueue {
+	return &headerQueue{}
+}
+
+func (q *headerQueue) put(h *types.Header) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	copy(q.items[1:], q.items[:len(q.items)-1])
+	q.items[0] = h
+}
+
+func (q *headerQueue) get(hash common.Hash) *types.Header {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, h := range q.items {
+		if h != nil && h.Hash() == hash {
+			return h
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package engineapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadQueueEviction(t *testing.T) {
+	q := newPayloadQueue()
+	ids := make([]engine.PayloadID, maxTrackedPayloads+1)
+	for i := range ids {
+		ids[i][0] = byte(i + 1)
+		q.put(&blockBuilder{id: ids[i], done: make(chan struct{})})
+	}
+
+	require.Nil(t, q.get(ids[0]), "oldest entry should have been evicted once the ring filled up")
+	for _, id := range ids[1:] {
+		require.NotNil(t, q.get(id), "recent entries should still be reachable by id")
+	}
+}
+
+func TestPayloadQueueEvictionClosesOldest(t *testing.T) {
+	q := newPayloadQueue()
+	oldest := &blockBuilder{done: make(chan struct{})}
+	q.put(oldest)
+	for i := 0; i < maxTrackedPayloads; i++ {
+		q.put(&blockBuilder{done: make(chan struct{})})
+	}
+
+	select {
+	case <-oldest.done:
+	default:
+		t.Fatal("expected evicted builder's done channel to be closed")
+	}
+}
+
+func TestHeaderQueuePutGet(t *testing.T) {
+	q := newHeaderQueue()
+	h := &types.Header{Number: big.NewInt(1)}
+	q.put(h)
+
+	require.Equal(t, h.Hash(), q.get(h.Hash()).Hash())
+	require.Nil(t, q.get((&types.Header{Number: big.NewInt(2)}).Hash()))
+}
+
+// TestBlockBuilderCloseRejectsFurtherTx is the regression test for c379fce: once a build has been
+// collected, includeTx must reject any transaction that the improve-payload loop was still in the
+// middle of packing, rather than mutating a build that GetPayload/endBlock may already be sealing.
+func TestBlockBuilderCloseRejectsFurtherTx(t *testing.T) {
+	b := &blockBuilder{done: make(chan struct{})}
+	require.False(t, b.collected)
+
+	b.close()
+	require.True(t, b.collected)
+	select {
+	case <-b.done:
+	default:
+		t.Fatal("expected done channel to be closed")
+	}
+	require.NotPanics(t, func() { b.close() }, "close must be idempotent")
+
+	ea := &L2EngineAPI{}
+	tx := types.NewTx(&types.LegacyTx{})
+	err := ea.includeTx(b, tx, common.Address{})
+	require.ErrorIs(t, err, ErrAlreadySealed)
+}
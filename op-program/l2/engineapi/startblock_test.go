@@ -0,0 +1,125 @@
+package engineapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/op-node/eth"
+)
+
+// newTestState returns a freshly initialized, empty state database, enough for startBlock to
+// succeed past its StateAt call without needing a real chain's trie database.
+func newTestState(t *testing.T) *state.StateDB {
+	t.Helper()
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, err := state.New(types.EmptyRootHash, db, nil)
+	require.NoError(t, err)
+	return statedb
+}
+
+func zeroTime() *uint64 {
+	t := uint64(0)
+	return &t
+}
+
+// TestStartBlockRejectsWithdrawalsBeforeShanghai covers the first of startBlock's four fork-gating
+// checks: payload attributes carrying withdrawals before Shanghai has activated must be rejected.
+func TestStartBlockRejectsWithdrawalsBeforeShanghai(t *testing.T) {
+	parent := &types.Header{Number: big.NewInt(1)}
+	backend := &fakeBackend{
+		config:  &params.ChainConfig{},
+		headers: map[common.Hash]*types.Header{parent.Hash(): parent},
+		stateDB: newTestState(t),
+	}
+	ea := newTestEngineAPI(t, backend)
+
+	withdrawals := types.Withdrawals{}
+	_, err := ea.startBlock(parent.Hash(), &eth.PayloadAttributes{Withdrawals: &withdrawals})
+	require.ErrorContains(t, err, "before Shanghai")
+}
+
+// TestStartBlockRequiresWithdrawalsOnAndAfterShanghai covers the second fork-gating check: payload
+// attributes missing withdrawals on or after Shanghai activation must be rejected.
+func TestStartBlockRequiresWithdrawalsOnAndAfterShanghai(t *testing.T) {
+	parent := &types.Header{Number: big.NewInt(1)}
+	backend := &fakeBackend{
+		config:  &params.ChainConfig{LondonBlock: big.NewInt(0), ShanghaiTime: zeroTime()},
+		headers: map[common.Hash]*types.Header{parent.Hash(): parent},
+		stateDB: newTestState(t),
+	}
+	ea := newTestEngineAPI(t, backend)
+
+	_, err := ea.startBlock(parent.Hash(), &eth.PayloadAttributes{})
+	require.ErrorContains(t, err, "missing withdrawals")
+}
+
+// TestStartBlockRejectsParentBeaconRootBeforeCancun covers the third fork-gating check: payload
+// attributes carrying a parent beacon block root before Cancun has activated must be rejected.
+func TestStartBlockRejectsParentBeaconRootBeforeCancun(t *testing.T) {
+	parent := &types.Header{Number: big.NewInt(1)}
+	backend := &fakeBackend{
+		config:  &params.ChainConfig{LondonBlock: big.NewInt(0), ShanghaiTime: zeroTime()},
+		headers: map[common.Hash]*types.Header{parent.Hash(): parent},
+		stateDB: newTestState(t),
+	}
+	ea := newTestEngineAPI(t, backend)
+
+	withdrawals := types.Withdrawals{}
+	root := common.HexToHash("0x01")
+	_, err := ea.startBlock(parent.Hash(), &eth.PayloadAttributes{Withdrawals: &withdrawals, ParentBeaconBlockRoot: &root})
+	require.ErrorContains(t, err, "before Cancun")
+}
+
+// TestStartBlockRequiresParentBeaconRootOnAndAfterCancun covers the fourth fork-gating check:
+// payload attributes missing a parent beacon block root on or after Cancun activation must be
+// rejected.
+func TestStartBlockRequiresParentBeaconRootOnAndAfterCancun(t *testing.T) {
+	parent := &types.Header{Number: big.NewInt(1)}
+	backend := &fakeBackend{
+		config:  &params.ChainConfig{LondonBlock: big.NewInt(0), ShanghaiTime: zeroTime(), CancunTime: zeroTime()},
+		headers: map[common.Hash]*types.Header{parent.Hash(): parent},
+		stateDB: newTestState(t),
+	}
+	ea := newTestEngineAPI(t, backend)
+
+	withdrawals := types.Withdrawals{}
+	_, err := ea.startBlock(parent.Hash(), &eth.PayloadAttributes{Withdrawals: &withdrawals})
+	require.ErrorContains(t, err, "missing parent beacon block root")
+}
+
+// TestEndBlockWithdrawalsHashAndGasUsed is the regression test for endBlock's bookkeeping: the
+// withdrawals hash must be derived from b.withdrawals, and GasUsed must reflect what was actually
+// spent from the gas pool rather than the full block gas limit.
+func TestEndBlockWithdrawalsHashAndGasUsed(t *testing.T) {
+	gasLimit := uint64(30_000_000)
+	gasPool := new(core.GasPool).AddGas(gasLimit)
+	gasPool.SubGas(21_000)
+
+	withdrawals := types.Withdrawals{{Index: 1, Validator: 2, Address: common.Address{0x1}, Amount: 3}}
+	b := &blockBuilder{
+		header: &types.Header{
+			Number:   big.NewInt(2),
+			GasLimit: gasLimit,
+		},
+		state:       newTestState(t),
+		gasPool:     gasPool,
+		withdrawals: withdrawals,
+		done:        make(chan struct{}),
+	}
+
+	block, err := endBlock(&fakeBackend{config: &params.ChainConfig{}}, b)
+	require.NoError(t, err)
+	require.Equal(t, uint64(21_000), block.GasUsed())
+	require.NotNil(t, block.Header().WithdrawalsHash)
+	require.Equal(t, types.DeriveSha(withdrawals, trie.NewStackTrie(nil)), *block.Header().WithdrawalsHash)
+	require.Equal(t, withdrawals, block.Withdrawals())
+}
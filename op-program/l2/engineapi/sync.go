@@ -0,0 +1,58 @@
+package engineapi
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ethereum-optimism/optimism/op-node/eth"
+)
+
+// beaconSyncStaleTimeout bounds how long the engine remembers a beacon-advertised head it
+// triggered a download towards, so a CL that requested a sync to an unreachable/bad head and then
+// moved on doesn't leave the node wedged waiting on it forever.
+const beaconSyncStaleTimeout = 30 * time.Second
+
+// beaconSync tracks a ForkchoiceUpdated-triggered reverse-header download towards a head the
+// engine doesn't have locally yet, mirroring upstream eth/catalyst's SYNCING/ACCEPTED bookkeeping.
+type beaconSync struct {
+	head      common.Hash
+	requested time.Time
+}
+
+// triggerBeaconSync kicks off (or refreshes) a download towards head via the backend's
+// Downloader-like sync hooks, forgetting any previously pending target that has gone stale.
+func (ea *L2EngineAPI) triggerBeaconSync(head common.Hash) (*eth.ForkchoiceUpdatedResult, error) {
+	if err := ea.maybeTriggerBeaconSync(head); err != nil {
+		return STATUS_INVALID, err
+	}
+	return STATUS_SYNCING, nil
+}
+
+// maybeTriggerBeaconSync is the pendingSync-aware core of triggerBeaconSync: it dedupes a sync
+// request against whatever is already pending towards head, forgetting it first if stale. Also
+// used by newPayload's missing-parent path, so a CL repeatedly resubmitting either call while this
+// engine catches up doesn't retrigger BeaconSync on every single call.
+func (ea *L2EngineAPI) maybeTriggerBeaconSync(head common.Hash) error {
+	ea.mu.Lock()
+	defer ea.mu.Unlock()
+	if ea.pendingSync != nil && time.Since(ea.pendingSync.requested) > beaconSyncStaleTimeout {
+		ea.log.Warn("Forgetting stale beacon sync target", "head", ea.pendingSync.head)
+		ea.pendingSync = nil
+	}
+	if ea.pendingSync == nil || ea.pendingSync.head != head {
+		if err := ea.backend.BeaconSync(head); err != nil {
+			return fmt.Errorf("failed to trigger beacon sync to %s: %w", head, err)
+		}
+		ea.pendingSync = &beaconSync{head: head, requested: time.Now()}
+	}
+	return nil
+}
+
+// SyncProgress reports the backend's view of any sync triggered by a prior ForkchoiceUpdated or
+// NewPayload call, or nil if the node isn't currently syncing.
+func (ea *L2EngineAPI) SyncProgress() *ethereum.SyncProgress {
+	return ea.backend.SyncProgress()
+}
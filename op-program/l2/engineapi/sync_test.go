@@ -0,0 +1,54 @@
+package engineapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTriggerBeaconSyncDedup ensures a CL repeatedly sending the same unsynced head in
+// ForkchoiceUpdated only triggers one BeaconSync call, and that a genuinely new target does
+// trigger another.
+func TestTriggerBeaconSyncDedup(t *testing.T) {
+	backend := &fakeBackend{}
+	ea := newTestEngineAPI(t, backend)
+	head := common.HexToHash("0x01")
+
+	_, err := ea.triggerBeaconSync(head)
+	require.NoError(t, err)
+	_, err = ea.triggerBeaconSync(head)
+	require.NoError(t, err)
+	require.Len(t, backend.beaconSyncCalls, 1, "a repeated FCU towards the same unsynced head must not retrigger BeaconSync")
+
+	other := common.HexToHash("0x02")
+	_, err = ea.triggerBeaconSync(other)
+	require.NoError(t, err)
+	require.Len(t, backend.beaconSyncCalls, 2, "a new target must trigger a fresh sync")
+}
+
+func TestTriggerBeaconSyncForgetsStaleTarget(t *testing.T) {
+	backend := &fakeBackend{}
+	ea := newTestEngineAPI(t, backend)
+	head := common.HexToHash("0x01")
+	ea.pendingSync = &beaconSync{head: head, requested: time.Now().Add(-beaconSyncStaleTimeout - time.Second)}
+
+	_, err := ea.triggerBeaconSync(head)
+	require.NoError(t, err)
+	require.Len(t, backend.beaconSyncCalls, 1, "a stale pending sync must be forgotten and retried rather than deduped away")
+}
+
+// TestNewPayloadMissingParentDedupsWithForkchoiceUpdated is the regression test for the
+// chunk0-6 follow-up fix: NewPayload's missing-parent path shares maybeTriggerBeaconSync with
+// ForkchoiceUpdated, so a sync already pending from one is deduped against a call from the other.
+func TestNewPayloadMissingParentDedupsWithForkchoiceUpdated(t *testing.T) {
+	backend := &fakeBackend{}
+	ea := newTestEngineAPI(t, backend)
+	head := common.HexToHash("0x01")
+
+	_, err := ea.triggerBeaconSync(head)
+	require.NoError(t, err)
+	require.NoError(t, ea.maybeTriggerBeaconSync(head))
+	require.Len(t, backend.beaconSyncCalls, 1)
+}